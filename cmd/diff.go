@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// blockHashSize is the edge length of the grid used for the coarse
+// pre-check: each image is reduced to an 8x8 grid of average colors
+// before any per-pixel comparison is attempted.
+const blockHashSize = 8
+
+// diffExceedsThreshold decides whether b differs enough from a to
+// warrant a wallpaper update. It first compares a cheap block-hash of
+// both images so identical (or near-identical) captures short-circuit
+// without a full pixel scan, then falls back to a per-pixel RGBA delta
+// if the block-hash suggests something changed.
+func diffExceedsThreshold(a, b []byte, threshold float64) (bool, float64, error) {
+	imgA, err := png.Decode(bytes.NewReader(a))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to decode previous screenshot: %w", err)
+	}
+	imgB, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to decode new screenshot: %w", err)
+	}
+
+	sameSize := imgA.Bounds().Dx() == imgB.Bounds().Dx() && imgA.Bounds().Dy() == imgB.Bounds().Dy()
+	if sameSize && blockHash(imgA) == blockHash(imgB) {
+		return false, 0, nil
+	}
+
+	ratio := pixelDiffRatio(imgA, imgB)
+	return ratio >= threshold, ratio, nil
+}
+
+// blockHash reduces img to a fixed-size grid of average colors, cheap
+// enough to compute every cycle and good enough to reject "nothing
+// changed" captures before paying for a full pixel diff.
+func blockHash(img image.Image) [blockHashSize * blockHashSize]color.RGBA64 {
+	var hash [blockHashSize * blockHashSize]color.RGBA64
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return hash
+	}
+
+	for by := 0; by < blockHashSize; by++ {
+		for bx := 0; bx < blockHashSize; bx++ {
+			x0 := bounds.Min.X + bx*w/blockHashSize
+			x1 := bounds.Min.X + (bx+1)*w/blockHashSize
+			y0 := bounds.Min.Y + by*h/blockHashSize
+			y1 := bounds.Min.Y + (by+1)*h/blockHashSize
+
+			var rSum, gSum, bSum, aSum, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, bl, a := img.At(x, y).RGBA()
+					rSum += uint64(r)
+					gSum += uint64(g)
+					bSum += uint64(bl)
+					aSum += uint64(a)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			hash[by*blockHashSize+bx] = color.RGBA64{
+				R: uint16(rSum / n),
+				G: uint16(gSum / n),
+				B: uint16(bSum / n),
+				A: uint16(aSum / n),
+			}
+		}
+	}
+	return hash
+}
+
+// pixelDiffRatio returns the fraction of pixels whose RGBA delta
+// exceeds a small per-channel noise floor, as a value in [0, 1].
+// Differently sized images are treated as 100% different.
+func pixelDiffRatio(a, b image.Image) float64 {
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	if boundsA.Dx() != boundsB.Dx() || boundsA.Dy() != boundsB.Dy() {
+		return 1
+	}
+
+	const noiseFloor = 8 << 8 // out of 16-bit channel range, absorbs PNG/JPEG re-encode jitter
+
+	w, h := boundsA.Dx(), boundsA.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+
+	var diffPixels int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r1, g1, b1, a1 := a.At(boundsA.Min.X+x, boundsA.Min.Y+y).RGBA()
+			r2, g2, b2, a2 := b.At(boundsB.Min.X+x, boundsB.Min.Y+y).RGBA()
+			if absDiff(r1, r2) > noiseFloor || absDiff(g1, g2) > noiseFloor ||
+				absDiff(b1, b2) > noiseFloor || absDiff(a1, a2) > noiseFloor {
+				diffPixels++
+			}
+		}
+	}
+
+	return float64(diffPixels) / float64(w*h)
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// writeDiffImage renders a per-pixel diff of a vs b (white where they
+// match, red where they differ) into dir, named after target.
+func writeDiffImage(dir, target string, a, b []byte) error {
+	imgA, err := png.Decode(bytes.NewReader(a))
+	if err != nil {
+		return fmt.Errorf("failed to decode previous screenshot: %w", err)
+	}
+	imgB, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed to decode new screenshot: %w", err)
+	}
+
+	boundsA, boundsB := imgA.Bounds(), imgB.Bounds()
+	w, h := boundsB.Dx(), boundsB.Dy()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	const noiseFloor = 8 << 8
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r2, g2, b2, a2 := imgB.At(boundsB.Min.X+x, boundsB.Min.Y+y).RGBA()
+			different := x >= boundsA.Dx() || y >= boundsA.Dy()
+			if !different {
+				r1, g1, b1, a1 := imgA.At(boundsA.Min.X+x, boundsA.Min.Y+y).RGBA()
+				different = absDiff(r1, r2) > noiseFloor || absDiff(g1, g2) > noiseFloor ||
+					absDiff(b1, b2) > noiseFloor || absDiff(a1, a2) > noiseFloor
+			}
+			if different {
+				out.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				out.Set(x, y, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create diff-out dir: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s_diff.png", sanitizeFilename(target)))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create diff image %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, out); err != nil {
+		return fmt.Errorf("failed to encode diff image %s: %w", path, err)
+	}
+
+	return nil
+}
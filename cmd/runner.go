@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Runner drives the capture loop: it owns the Chrome Session, the
+// per-target diff state used to skip no-op lockscreen updates, and the
+// knobs that control that gating.
+type Runner struct {
+	session   *Session
+	wallpaper Wallpaper
+	dir       string
+
+	diffThreshold float64
+	diffOut       string
+	force         bool
+
+	previous map[string][]byte // target name -> last captured PNG
+}
+
+// NewRunner builds a Runner writing screenshots under dir and applying
+// them via wallpaper.
+func NewRunner(session *Session, wallpaper Wallpaper, dir string, diffThreshold float64, diffOut string, force bool) *Runner {
+	return &Runner{
+		session:       session,
+		wallpaper:     wallpaper,
+		dir:           dir,
+		diffThreshold: diffThreshold,
+		diffOut:       diffOut,
+		force:         force,
+		previous:      map[string][]byte{},
+	}
+}
+
+// Loop runs targets forever, trying each one up to 3 times on
+// failure, sleeping sleepInterval between full passes.
+func (r *Runner) Loop(targets []Target, sleepInterval time.Duration) {
+	for {
+		for _, target := range targets {
+			var lastErr error
+			for attempts := 0; attempts < 3; attempts++ {
+				if attempts > 0 {
+					log.Printf("Retry attempt %d/3 after failure", attempts+1)
+					time.Sleep(30 * time.Second) // Wait between retries
+				}
+
+				if err := r.runOnce(target); err != nil {
+					lastErr = err
+					log.Printf("Attempt %d failed with error: %v", attempts+1, err)
+					continue
+				}
+				lastErr = nil
+				break
+			}
+
+			if lastErr != nil {
+				log.Printf("All attempts failed for %s. Last error: %v", target.Name, lastErr)
+			} else {
+				log.Printf("Successfully updated %s", target.Name)
+			}
+		}
+
+		log.Printf("Waiting %s before next update...", sleepInterval)
+		time.Sleep(sleepInterval)
+	}
+}
+
+// runOnce captures target, skips the wallpaper update if the result is
+// visually indistinguishable from the last capture of this target, and
+// otherwise routes the screenshot per target.Output.
+func (r *Runner) runOnce(target Target) error {
+	if !r.session.Alive() {
+		log.Println("Chrome session is dead, recreating it...")
+		if err := r.session.Recreate(); err != nil {
+			return fmt.Errorf("failed to recreate dead session: %w", err)
+		}
+	}
+
+	log.Printf("Capturing %s...", target.Name)
+
+	buf, err := r.session.CaptureTarget(target)
+	if err != nil {
+		return err
+	}
+
+	screenshotPath := filepath.Join(r.dir, fmt.Sprintf("%s_%s.png", sanitizeFilename(target.Name), time.Now().Format("20060102_150405")))
+	if err := os.WriteFile(screenshotPath, buf, 0644); err != nil {
+		return fmt.Errorf("failed to save screenshot: %w", err)
+	}
+	log.Printf("Saved screenshot to: %s", screenshotPath)
+
+	if !r.force {
+		if prev, ok := r.previous[target.Name]; ok {
+			changed, ratio, err := diffExceedsThreshold(prev, buf, r.diffThreshold)
+			if err != nil {
+				log.Printf("Diff against previous capture of %s failed, proceeding anyway: %v", target.Name, err)
+			} else if !changed {
+				log.Printf("No change for %s (diff ratio %.4f < threshold %.4f), skipping wallpaper update", target.Name, ratio, r.diffThreshold)
+				r.previous[target.Name] = buf
+				return nil
+			} else {
+				log.Printf("Change detected for %s (diff ratio %.4f)", target.Name, ratio)
+				if r.diffOut != "" {
+					if err := writeDiffImage(r.diffOut, target.Name, prev, buf); err != nil {
+						log.Printf("Failed to write diff image for %s: %v", target.Name, err)
+					}
+				}
+			}
+		}
+	}
+	r.previous[target.Name] = buf
+
+	return r.applyOutput(target, screenshotPath)
+}
+
+// applyOutput routes a saved screenshot according to target.Output:
+// "lockscreen" sets the lock screen, "desktop" sets the desktop
+// background, and "save" just leaves the file on disk.
+func (r *Runner) applyOutput(target Target, screenshotPath string) error {
+	switch target.Output {
+	case "desktop":
+		log.Println("Setting as desktop background...")
+		if err := r.wallpaper.SetDesktop(screenshotPath); err != nil {
+			return fmt.Errorf("failed to set desktop background: %w", err)
+		}
+	case "save":
+		// Nothing further to do; the file is already on disk.
+	default:
+		log.Println("Setting as lock screen...")
+		if err := r.wallpaper.SetLockScreen(screenshotPath); err != nil {
+			return fmt.Errorf("failed to set lock screen: %w", err)
+		}
+	}
+	return nil
+}
+
+// sanitizeFilename replaces characters that aren't safe to use directly
+// in a filename, such as the slashes and colons in a URL, with "_".
+func sanitizeFilename(name string) string {
+	replacer := strings.NewReplacer(
+		"://", "_",
+		"/", "_",
+		":", "_",
+		"?", "_",
+		"&", "_",
+	)
+	return replacer.Replace(name)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Target describes one page to capture each cycle: where to navigate,
+// how to size and authenticate the request, and where the resulting
+// screenshot should end up. Scripts describe a slice of these so a
+// single binary can rotate several pages onto the lockscreen/desktop,
+// or capture two origins for an A/B compare and only apply one.
+type Target struct {
+	Name         string
+	URL          string
+	Width        int
+	Height       int
+	WaitSelector string
+	Headers      map[string]string
+	Output       string // "lockscreen", "desktop", or "save"
+	Capture      string // "fullscreen" or "viewport"
+}
+
+// ParseScript reads a target script from path. The format is a series
+// of blocks separated by blank lines; each block is one Target built
+// from directives of the form "<name> <value>":
+//
+//	origin https://staging.example.com
+//	pathname /status
+//	windowsize 3440x1080
+//	wait #ready
+//	header Authorization: Bearer xyz
+//	capture fullscreen
+//	output lockscreen
+//
+// "url" may be given instead of "origin"/"pathname" to specify the
+// full address directly. Lines starting with "#" are comments.
+func ParseScript(path string) ([]Target, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var targets []Target
+	cur := newTarget()
+	haveContent := false
+
+	flush := func() error {
+		if !haveContent {
+			return nil
+		}
+		t, err := cur.build()
+		if err != nil {
+			return err
+		}
+		targets = append(targets, t)
+		cur = newTarget()
+		haveContent = false
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if err := flush(); err != nil {
+				return nil, fmt.Errorf("script %s: %w", path, err)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		directive, rest, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("script %s line %d: missing value for %q", path, lineNo, line)
+		}
+		rest = strings.TrimSpace(rest)
+
+		if err := cur.apply(directive, rest); err != nil {
+			return nil, fmt.Errorf("script %s line %d: %w", path, lineNo, err)
+		}
+		haveContent = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script %s: %w", path, err)
+	}
+	if err := flush(); err != nil {
+		return nil, fmt.Errorf("script %s: %w", path, err)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("script %s defined no targets", path)
+	}
+	return targets, nil
+}
+
+// builder accumulates directives for one in-progress Target.
+type builder struct {
+	origin       string
+	pathname     string
+	url          string
+	width        int
+	height       int
+	waitSelector string
+	headers      map[string]string
+	output       string
+	capture      string
+}
+
+func newTarget() *builder {
+	return &builder{
+		pathname: "/",
+		output:   "lockscreen",
+		capture:  "fullscreen",
+		headers:  map[string]string{},
+	}
+}
+
+func (b *builder) apply(directive, value string) error {
+	switch directive {
+	case "origin":
+		b.origin = value
+	case "pathname":
+		b.pathname = value
+	case "url":
+		b.url = value
+	case "windowsize":
+		w, h, err := parseWindowSize(value)
+		if err != nil {
+			return err
+		}
+		b.width, b.height = w, h
+	case "wait":
+		b.waitSelector = value
+	case "header":
+		name, val, ok := strings.Cut(value, ":")
+		if !ok {
+			return fmt.Errorf("header directive must be \"Name: value\", got %q", value)
+		}
+		b.headers[strings.TrimSpace(name)] = strings.TrimSpace(val)
+	case "output":
+		switch value {
+		case "lockscreen", "desktop", "save":
+			b.output = value
+		default:
+			return fmt.Errorf("unknown output %q (want lockscreen, desktop, or save)", value)
+		}
+	case "capture":
+		switch value {
+		case "fullscreen", "viewport":
+			b.capture = value
+		default:
+			return fmt.Errorf("unknown capture mode %q (want fullscreen or viewport)", value)
+		}
+	default:
+		return fmt.Errorf("unknown directive %q", directive)
+	}
+	return nil
+}
+
+func (b *builder) build() (Target, error) {
+	target := Target{
+		Width:        b.width,
+		Height:       b.height,
+		WaitSelector: b.waitSelector,
+		Headers:      b.headers,
+		Output:       b.output,
+		Capture:      b.capture,
+	}
+
+	switch {
+	case b.url != "":
+		target.URL = b.url
+	case b.origin != "":
+		u, err := url.Parse(b.origin)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid origin %q: %w", b.origin, err)
+		}
+		u.Path = b.pathname
+		target.URL = u.String()
+	default:
+		return Target{}, fmt.Errorf("target has no url or origin")
+	}
+
+	target.Name = target.URL
+	return target, nil
+}
+
+func parseWindowSize(value string) (int, int, error) {
+	w, h, ok := strings.Cut(value, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("windowsize must be WxH, got %q", value)
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid windowsize width %q: %w", w, err)
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid windowsize height %q: %w", h, err)
+	}
+	return width, height, nil
+}
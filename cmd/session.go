@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Session owns a long-lived Chrome instance backed by a persistent
+// user-data-dir, so cookies and logins survive across capture cycles
+// instead of being thrown away every interval.
+type Session struct {
+	profileDir string
+	dev        bool
+	width      int
+	height     int
+
+	allocCtx      context.Context
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+
+	waitStrategy WaitStrategy
+}
+
+// NewSession starts (or resumes, if profileDir already has one) a Chrome
+// user-data-dir and keeps the allocator alive for the lifetime of the
+// Session. Pass dev=true to run headful, which is useful for watching
+// the page render or logging in by hand the first time.
+func NewSession(profileDir string, dev bool, width, height int) (*Session, error) {
+	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+		chromedp.Flag("headless", !dev),
+		chromedp.Flag("disable-gpu", true),
+		chromedp.Flag("no-sandbox", true),
+		chromedp.Flag("disable-dev-shm-usage", true),
+		chromedp.Flag("disable-setuid-sandbox", true),
+		chromedp.Flag("disable-web-security", true),
+		chromedp.Flag("disable-background-networking", false),
+		chromedp.Flag("enable-features", "NetworkService,NetworkServiceInProcess"),
+		chromedp.Flag("ignore-certificate-errors", true),
+		chromedp.UserDataDir(profileDir),
+		chromedp.WindowSize(width, height),
+	)
+
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
+
+	browserCtx, browserCancel := chromedp.NewContext(
+		allocCtx,
+		chromedp.WithLogf(func(format string, args ...interface{}) {
+			log.Printf("Browser: "+format, args...)
+		}),
+		chromedp.WithErrorf(func(format string, args ...interface{}) {
+			log.Printf("Browser Error: "+format, args...)
+		}),
+	)
+
+	// Start the browser now so failures surface immediately rather than
+	// on the first Capture call.
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, fmt.Errorf("failed to start persistent Chrome session: %w", err)
+	}
+
+	return &Session{
+		profileDir:    profileDir,
+		dev:           dev,
+		width:         width,
+		height:        height,
+		allocCtx:      allocCtx,
+		allocCancel:   allocCancel,
+		browserCtx:    browserCtx,
+		browserCancel: browserCancel,
+		waitStrategy:  DefaultWaitStrategy(750*time.Millisecond, ""),
+	}, nil
+}
+
+// SetWaitStrategy overrides how CaptureTarget decides a page is ready
+// to screenshot. Defaults to DefaultWaitStrategy.
+func (s *Session) SetWaitStrategy(ws WaitStrategy) {
+	s.waitStrategy = ws
+}
+
+// NewContext returns a fresh chromedp context backed by this Session's
+// browser, scoped to the given timeout. Callers should cancel the
+// returned context when a capture is done; the underlying browser stays
+// alive for reuse.
+func (s *Session) NewContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(s.browserCtx, timeout)
+	return ctx, cancel
+}
+
+// Shutdown tears down the browser and allocator. The profile directory
+// on disk is left intact so the next Session reuses its cookies/logins.
+func (s *Session) Shutdown() {
+	s.browserCancel()
+	s.allocCancel()
+}
+
+// Alive reports whether the underlying browser context is still live.
+// It goes false once the browser crashes, the allocator dies, or
+// anything else cancels browserCtx — at which point every future
+// CaptureTarget call would fail forever without a new Session.
+func (s *Session) Alive() bool {
+	select {
+	case <-s.browserCtx.Done():
+		return false
+	default:
+		return true
+	}
+}
+
+// Recreate tears down the current browser/allocator and starts a fresh
+// one with the same profile dir, dev mode, window size, and wait
+// strategy, for use after Alive reports the browser has died. The
+// persistent profile dir means cookies/logins survive the restart.
+func (s *Session) Recreate() error {
+	s.Shutdown()
+
+	fresh, err := NewSession(s.profileDir, s.dev, s.width, s.height)
+	if err != nil {
+		return fmt.Errorf("failed to recreate Chrome session: %w", err)
+	}
+	fresh.waitStrategy = s.waitStrategy
+
+	*s = *fresh
+	return nil
+}
+
+// Capture navigates to url in this Session's browser and returns a full
+// page screenshot, using the Session's default window size.
+func (s *Session) Capture(url string) ([]byte, error) {
+	return s.CaptureTarget(Target{URL: url})
+}
+
+// CaptureTarget navigates to t.URL and returns a screenshot, applying
+// any per-target window size, extra headers, and wait selector. Fields
+// left zero fall back to the Session's defaults.
+func (s *Session) CaptureTarget(t Target) ([]byte, error) {
+	width, height := s.width, s.height
+	if t.Width > 0 && t.Height > 0 {
+		width, height = t.Width, t.Height
+	}
+
+	headers := network.Headers{
+		"User-Agent": "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36",
+	}
+	for k, v := range t.Headers {
+		headers[k] = v
+	}
+
+	ctx, cancel := s.NewContext(3 * time.Minute)
+	defer cancel()
+
+	waitSelector := "body"
+	if t.WaitSelector != "" {
+		waitSelector = t.WaitSelector
+	}
+
+	actions := []chromedp.Action{
+		network.Enable(),
+		network.SetExtraHTTPHeaders(headers),
+		chromedp.EmulateViewport(int64(width), int64(height)),
+		chromedp.Navigate(t.URL),
+		chromedp.WaitVisible(waitSelector, chromedp.ByQuery),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			return s.waitStrategy.Wait(ctx)
+		}),
+	}
+
+	var buf []byte
+	if t.Capture == "viewport" {
+		actions = append(actions, chromedp.CaptureScreenshot(&buf))
+	} else {
+		actions = append(actions, chromedp.FullScreenshot(&buf, 100))
+	}
+
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return nil, fmt.Errorf("failed to capture %s: %w", t.URL, err)
+	}
+
+	return buf, nil
+}
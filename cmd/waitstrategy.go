@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// WaitStrategy decides when a navigated page is "ready enough" to
+// screenshot. A fixed sleep is the simplest implementation but
+// produces wrong-page captures under load; NetworkIdleStrategy and
+// SelectorStrategy are usually better, and strategies compose via
+// Sequence so e.g. network-idle can be followed by a selector check.
+type WaitStrategy interface {
+	Wait(ctx context.Context) error
+}
+
+type waitFunc func(ctx context.Context) error
+
+func (f waitFunc) Wait(ctx context.Context) error { return f(ctx) }
+
+// FixedSleep waits for a flat duration, regardless of page state. This
+// is the strategy the tool used to hard-code; kept around as the
+// simplest opt-out via a custom WaitStrategy.
+func FixedSleep(d time.Duration) WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		return chromedp.Sleep(d).Do(ctx)
+	})
+}
+
+// SelectorStrategy waits until selector is visible in the page.
+func SelectorStrategy(selector string) WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		return chromedp.WaitVisible(selector, chromedp.ByQuery).Do(ctx)
+	})
+}
+
+// ReadyStateStrategy polls document.readyState until it reports
+// "complete".
+func ReadyStateStrategy() WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		for {
+			var state string
+			if err := chromedp.Evaluate(`document.readyState`, &state).Do(ctx); err != nil {
+				return err
+			}
+			if state == "complete" {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// NetworkIdleStrategy waits for the page's network activity to go
+// quiet: it listens for network.EventLoadingFinished/EventLoadingFailed
+// and only returns once quietPeriod has elapsed with no new events,
+// which avoids capturing a page mid-load the way a fixed sleep can.
+func NetworkIdleStrategy(quietPeriod time.Duration) WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		var mu sync.Mutex
+		idle := make(chan struct{})
+		var closeOnce sync.Once
+		signalIdle := func() {
+			closeOnce.Do(func() { close(idle) })
+		}
+
+		timer := time.AfterFunc(quietPeriod, signalIdle)
+		defer timer.Stop()
+
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev.(type) {
+			case *network.EventLoadingFinished, *network.EventLoadingFailed:
+				mu.Lock()
+				timer.Reset(quietPeriod)
+				mu.Unlock()
+			}
+		})
+
+		select {
+		case <-idle:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// JSPredicateStrategy waits until the given JavaScript expression
+// evaluates truthy, for readiness conditions a selector or
+// readyState/network-idle check can't express.
+func JSPredicateStrategy(expr string) WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		for {
+			var ready bool
+			if err := chromedp.Evaluate(expr, &ready).Do(ctx); err != nil {
+				return err
+			}
+			if ready {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+	})
+}
+
+// Sequence runs strategies in order, stopping at the first error.
+func Sequence(strategies ...WaitStrategy) WaitStrategy {
+	return waitFunc(func(ctx context.Context) error {
+		for _, s := range strategies {
+			if err := s.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DefaultWaitStrategy is network-idle followed by a readyState poll,
+// the combination that replaced the tool's original fixed 5s sleep. If
+// readySelector is non-empty it's appended as a final check.
+func DefaultWaitStrategy(quietPeriod time.Duration, readySelector string) WaitStrategy {
+	strategies := []WaitStrategy{NetworkIdleStrategy(quietPeriod), ReadyStateStrategy()}
+	if readySelector != "" {
+		strategies = append(strategies, SelectorStrategy(readySelector))
+	}
+	return Sequence(strategies...)
+}
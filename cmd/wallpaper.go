@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// Wallpaper applies a screenshot to the lock screen or desktop
+// background. Implementations are selected per-OS by NewWallpaper;
+// lock screen and desktop are kept as separate methods because on some
+// platforms only one of the two is actually settable, and callers
+// should know which happened rather than have the tool silently fall
+// back from one to the other.
+type Wallpaper interface {
+	SetLockScreen(path string) error
+	SetDesktop(path string) error
+}
+
+// NewWallpaper returns a Wallpaper backend for the given name
+// ("macos", "linux", or "windows"). An empty backend auto-selects based
+// on runtime.GOOS.
+func NewWallpaper(backend string) (Wallpaper, error) {
+	if backend == "" {
+		backend = runtime.GOOS
+	}
+
+	switch backend {
+	case "macos", "darwin":
+		return macOSWallpaper{}, nil
+	case "linux":
+		return newLinuxWallpaper(), nil
+	case "windows":
+		return newWindowsWallpaper(), nil
+	default:
+		return nil, fmt.Errorf("unsupported wallpaper backend %q", backend)
+	}
+}
+
+// macOSWallpaper drives System Events/Finder via osascript, the
+// approach the tool has always used on macOS.
+type macOSWallpaper struct{}
+
+func (macOSWallpaper) SetLockScreen(path string) error {
+	lockCmd := `osascript -e '
+        try
+            tell application "System Events"
+                tell every desktop
+                    set pictures folder to "` + filepath.Dir(path) + `"
+                    set picture to "` + path + `"
+                end tell
+            end tell
+            return "Success"
+        on error errMsg
+            return "Error: " & errMsg
+        end try'`
+
+	output, err := exec.Command("bash", "-c", lockCmd).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("lock screen command failed: %v, output: %s", err, string(output))
+	}
+	if string(output) != "Success" {
+		return fmt.Errorf("lock screen command reported failure: %s", string(output))
+	}
+	return nil
+}
+
+func (macOSWallpaper) SetDesktop(path string) error {
+	desktopCmd := `osascript -e '
+        try
+            tell application "Finder"
+                set desktop picture to POSIX file "` + path + `"
+            end tell
+            return "Success"
+        on error errMsg
+            return "Error: " & errMsg
+        end try'`
+
+	output, err := exec.Command("bash", "-c", desktopCmd).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("desktop background command failed: %v, output: %s", err, string(output))
+	}
+	if string(output) != "Success" {
+		return fmt.Errorf("desktop background command reported failure: %s", string(output))
+	}
+	return nil
+}
+
+// linuxWallpaper sets the desktop background via whichever tool is
+// available on $PATH: gsettings (GNOME), feh (lightweight WMs), or
+// swaybg (Sway/wlroots). Linux has no standard lock screen wallpaper
+// API, so SetLockScreen reports that explicitly rather than silently
+// setting the desktop instead.
+type linuxWallpaper struct {
+	tool string // "gsettings", "feh", or "swaybg"
+
+	swaybgProc *exec.Cmd // previously started swaybg instance, if any
+}
+
+func newLinuxWallpaper() *linuxWallpaper {
+	for _, tool := range []string{"gsettings", "feh", "swaybg"} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return &linuxWallpaper{tool: tool}
+		}
+	}
+	return &linuxWallpaper{}
+}
+
+func (w *linuxWallpaper) SetLockScreen(path string) error {
+	return fmt.Errorf("no lock screen backend available on linux; use \"output desktop\" for this target in a -script file instead")
+}
+
+func (w *linuxWallpaper) SetDesktop(path string) error {
+	switch w.tool {
+	case "gsettings":
+		uri := "file://" + path
+		for _, key := range []string{"picture-uri", "picture-uri-dark"} {
+			if out, err := exec.Command("gsettings", "set", "org.gnome.desktop.background", key, uri).CombinedOutput(); err != nil {
+				return fmt.Errorf("gsettings set %s failed: %v, output: %s", key, err, string(out))
+			}
+		}
+		return nil
+	case "feh":
+		if out, err := exec.Command("feh", "--bg-fill", path).CombinedOutput(); err != nil {
+			return fmt.Errorf("feh --bg-fill failed: %v, output: %s", err, string(out))
+		}
+		return nil
+	case "swaybg":
+		// swaybg has no "replace the running instance" primitive, so
+		// kill the one we started last cycle before relaunching it
+		// pointed at the new image; otherwise instances pile up and
+		// compete over the output.
+		w.killSwaybg()
+		cmd := exec.Command("swaybg", "-i", path, "-m", "fill")
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("swaybg failed to start: %w", err)
+		}
+		w.swaybgProc = cmd
+		return nil
+	default:
+		return fmt.Errorf("no desktop background tool found on PATH (tried gsettings, feh, swaybg)")
+	}
+}
+
+// killSwaybg stops and reaps the previously started swaybg instance,
+// if there is one.
+func (w *linuxWallpaper) killSwaybg() {
+	if w.swaybgProc == nil {
+		return
+	}
+	_ = w.swaybgProc.Process.Kill()
+	_ = w.swaybgProc.Wait()
+	w.swaybgProc = nil
+}
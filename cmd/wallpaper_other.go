@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import "fmt"
+
+// windowsWallpaper is a stub on non-Windows builds so NewWallpaper can
+// reference newWindowsWallpaper regardless of the host GOOS (used when
+// -backend windows is forced for testing a script against a Windows
+// target from another platform).
+type windowsWallpaper struct{}
+
+func newWindowsWallpaper() windowsWallpaper {
+	return windowsWallpaper{}
+}
+
+func (windowsWallpaper) SetLockScreen(path string) error {
+	return fmt.Errorf("windows backend is not available on this platform")
+}
+
+func (windowsWallpaper) SetDesktop(path string) error {
+	return fmt.Errorf("windows backend is not available on this platform")
+}
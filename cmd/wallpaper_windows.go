@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// windowsWallpaper sets the desktop background via
+// SystemParametersInfo. Windows has no separate lock-screen wallpaper
+// API exposed to ordinary processes (the lock screen image is managed
+// by the Settings app / group policy), so SetLockScreen reports that
+// rather than silently setting the desktop instead.
+type windowsWallpaper struct{}
+
+func newWindowsWallpaper() windowsWallpaper {
+	return windowsWallpaper{}
+}
+
+const (
+	spiSetDeskWallpaper = 0x0014
+	spifUpdateIniFile   = 0x01
+	spifSendChange      = 0x02
+)
+
+func (windowsWallpaper) SetLockScreen(path string) error {
+	return fmt.Errorf("no lock screen backend available on windows; use \"output desktop\" for this target in a -script file instead")
+}
+
+func (windowsWallpaper) SetDesktop(path string) error {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fmt.Errorf("invalid wallpaper path %q: %w", path, err)
+	}
+
+	ret, _, callErr := windows.NewLazySystemDLL("user32.dll").NewProc("SystemParametersInfoW").Call(
+		uintptr(spiSetDeskWallpaper),
+		0,
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(spifUpdateIniFile|spifSendChange),
+	)
+	if ret == 0 {
+		return fmt.Errorf("SystemParametersInfoW failed: %w", callErr)
+	}
+	return nil
+}